@@ -0,0 +1,126 @@
+// Package format provides the small, embedded subset of CLDR-style locale data wallace needs: number and
+// currency formatting conventions, and Gregorian month names, so the schedule can be produced in something
+// other than US English without reaching out to the network at runtime.
+package format
+
+import (
+	"strings"
+	"time"
+
+	"github.com/leekchan/accounting"
+)
+
+// DefaultLocale is used whenever a requested locale isn't in the embedded subset.
+const DefaultLocale = "en_US"
+
+// englishMonthNames are the month name tokens Go's time package itself understands; a Locale's month names
+// are substituted for these (and back) so stdlib time.Parse/Format can do the actual work.
+var englishMonthNames = [12]string{
+	"January", "February", "March", "April", "May", "June",
+	"July", "August", "September", "October", "November", "December",
+}
+
+// Locale is the formatting conventions for one locale: how numbers and currency amounts are punctuated, and
+// what the Gregorian months are called.
+type Locale struct {
+	Code              string
+	ThousandSeparator string
+	DecimalSeparator  string
+	// MinusSign prefixes Format to build accounting.Accounting's FormatNegative. Defaults to "-" if empty.
+	MinusSign string
+	// Format follows accounting.Accounting's own mini-language: %s is the currency symbol, %v is the amount.
+	Format     string
+	MonthNames [12]string
+}
+
+var locales = map[string]Locale{
+	"en_US": {
+		Code: "en_US", ThousandSeparator: ",", DecimalSeparator: ".", MinusSign: "-", Format: "%s%v",
+		MonthNames: englishMonthNames,
+	},
+	"fr_CA": {
+		Code: "fr_CA", ThousandSeparator: " ", DecimalSeparator: ",", MinusSign: "-", Format: "%v %s",
+		MonthNames: [12]string{
+			"janvier", "février", "mars", "avril", "mai", "juin",
+			"juillet", "août", "septembre", "octobre", "novembre", "décembre",
+		},
+	},
+	"rw_RW": {
+		Code: "rw_RW", ThousandSeparator: ",", DecimalSeparator: ".", MinusSign: "-", Format: "%v %s",
+		MonthNames: [12]string{
+			"Mutarama", "Gashyantare", "Werurwe", "Mata", "Gicurasi", "Kamena",
+			"Nyakanga", "Kanama", "Nzeri", "Ukwakira", "Ugushyingo", "Ukuboza",
+		},
+	},
+}
+
+// currencySymbols maps a handful of ISO 4217 currency codes to their display symbol. A code not in this
+// table is displayed as-is.
+var currencySymbols = map[string]string{
+	"USD": "$",
+	"CAD": "$",
+	"EUR": "€",
+	"GBP": "£",
+	"RWF": "FRw",
+}
+
+// CurrencySymbol returns the display symbol for an ISO 4217 currency code, falling back to the code itself
+// if it isn't in the embedded table.
+func CurrencySymbol(code string) string {
+	if symbol, ok := currencySymbols[code]; ok {
+		return symbol
+	}
+	return code
+}
+
+// Lookup returns the embedded Locale for code, and whether it was found.
+func Lookup(code string) (Locale, bool) {
+	l, ok := locales[code]
+	return l, ok
+}
+
+// NewAccounting builds an accounting.Accounting that formats amounts in symbol using this locale's
+// separator and sign conventions.
+func (l Locale) NewAccounting(symbol string, precision int) accounting.Accounting {
+	minusSign := l.MinusSign
+	if minusSign == "" {
+		minusSign = "-"
+	}
+
+	return accounting.Accounting{
+		Symbol:         symbol,
+		Precision:      precision,
+		Thousand:       l.ThousandSeparator,
+		Decimal:        l.DecimalSeparator,
+		Format:         l.Format,
+		FormatNegative: minusSign + l.Format,
+	}
+}
+
+// ParseDate parses value using layout (a Go reference-time layout using the English long month name, e.g.
+// "January 2 2006"), accepting this locale's wide month name in addition to the English one.
+func (l Locale) ParseDate(layout, value string) (time.Time, error) {
+	return time.ParseInLocation(layout, l.toEnglishMonth(value), time.UTC)
+}
+
+// FormatDate formats t using layout (a Go reference-time layout using the English long month name), with
+// this locale's wide month name substituted in place of the English one.
+func (l Locale) FormatDate(layout string, t time.Time) string {
+	formatted := t.Format(layout)
+	monthIndex := int(t.Month()) - 1
+	localName := l.MonthNames[monthIndex]
+	if localName == "" || localName == englishMonthNames[monthIndex] {
+		return formatted
+	}
+	return strings.Replace(formatted, englishMonthNames[monthIndex], localName, 1)
+}
+
+func (l Locale) toEnglishMonth(value string) string {
+	for i, name := range l.MonthNames {
+		if name == "" || name == englishMonthNames[i] || !strings.Contains(value, name) {
+			continue
+		}
+		return strings.Replace(value, name, englishMonthNames[i], 1)
+	}
+	return value
+}
@@ -0,0 +1,87 @@
+package format
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const layout = "January 2 2006"
+
+func TestLookupKnownLocales(t *testing.T) {
+	for _, code := range []string{"en_US", "fr_CA", "rw_RW"} {
+		l, ok := Lookup(code)
+		if !ok {
+			t.Errorf("Lookup(%q) not found", code)
+			continue
+		}
+		if l.Code != code {
+			t.Errorf("Lookup(%q).Code = %q, want %q", code, l.Code, code)
+		}
+	}
+}
+
+// TestLookupUnknownLocaleFallsBackToDefault covers the fallback path runSchedule relies on: an unrecognized
+// locale code reports !ok, and the caller is expected to retry with DefaultLocale, which must resolve.
+func TestLookupUnknownLocaleFallsBackToDefault(t *testing.T) {
+	if _, ok := Lookup("xx_XX"); ok {
+		t.Fatalf("Lookup(\"xx_XX\") unexpectedly found")
+	}
+
+	fallback, ok := Lookup(DefaultLocale)
+	if !ok {
+		t.Fatalf("Lookup(DefaultLocale) = %q not found", DefaultLocale)
+	}
+	if fallback.Code != DefaultLocale {
+		t.Errorf("fallback locale Code = %q, want %q", fallback.Code, DefaultLocale)
+	}
+}
+
+// TestFormatDateAndParseDateRoundTrip covers every month of every embedded locale: FormatDate must render
+// the locale's own month name, and ParseDate must read that same rendering back to the original date.
+func TestFormatDateAndParseDateRoundTrip(t *testing.T) {
+	for _, code := range []string{"en_US", "fr_CA", "rw_RW"} {
+		l, ok := Lookup(code)
+		if !ok {
+			t.Fatalf("Lookup(%q) not found", code)
+		}
+
+		for month := time.January; month <= time.December; month++ {
+			date := time.Date(2020, month, 15, 0, 0, 0, 0, time.UTC)
+
+			formatted := l.FormatDate(layout, date)
+			wantMonthName := l.MonthNames[int(month)-1]
+			if wantMonthName != "" && wantMonthName != month.String() && !strings.Contains(formatted, wantMonthName) {
+				t.Errorf("%s: FormatDate(%s) = %q, want it to contain %q", code, date, formatted, wantMonthName)
+			}
+
+			parsed, err := l.ParseDate(layout, formatted)
+			if err != nil {
+				t.Errorf("%s: ParseDate(%q) returned error: %s", code, formatted, err)
+				continue
+			}
+			if !parsed.Equal(date) {
+				t.Errorf("%s: ParseDate(FormatDate(%s)) = %s, want %s", code, date, parsed, date)
+			}
+		}
+	}
+}
+
+// TestParseDateAcceptsEnglishMonthNames covers the requirement that getMonthYearDate/getLumpSums keep
+// accepting the English month name even when a non-English locale is active.
+func TestParseDateAcceptsEnglishMonthNames(t *testing.T) {
+	l, ok := Lookup("fr_CA")
+	if !ok {
+		t.Fatalf("Lookup(\"fr_CA\") not found")
+	}
+
+	parsed, err := l.ParseDate(layout, "March 15 2020")
+	if err != nil {
+		t.Fatalf("ParseDate returned error: %s", err)
+	}
+
+	want := time.Date(2020, time.March, 15, 0, 0, 0, 0, time.UTC)
+	if !parsed.Equal(want) {
+		t.Errorf("ParseDate(%q) = %s, want %s", "March 15 2020", parsed, want)
+	}
+}
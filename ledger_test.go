@@ -0,0 +1,209 @@
+package main
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexandre-normand/wallace/internal/format"
+	"github.com/alexandre-normand/wallace/pkg/amortize"
+	"github.com/leekchan/accounting"
+)
+
+func TestLedgerScheduleWriterLoanRow(t *testing.T) {
+	currency = accounting.Accounting{Symbol: "$", Thousand: ",", Precision: 2}
+
+	w := newLedgerScheduleWriter("Liabilities:Mortgage", "Expenses:Interest:Mortgage", "Assets:Checking")
+	periodDate := time.Date(2020, time.February, 15, 0, 0, 0, 0, time.UTC)
+	if err := w.WriteRow(periodDate, "loan", amortize.NewMoneyFromFloat64(666.67), amortize.NewMoneyFromFloat64(16363.31), amortize.NewMoneyFromFloat64(17029.98), amortize.NewMoneyFromFloat64(183636.69)); err != nil {
+		t.Fatalf("WriteRow returned error: %s", err)
+	}
+
+	out, err := w.Render()
+	if err != nil {
+		t.Fatalf("Render returned error: %s", err)
+	}
+
+	want := "2020/02/15 Loan payment\n    Expenses:Interest:Mortgage  $666.67\n    Liabilities:Mortgage  $16,363.31\n    Assets:Checking\n\n"
+	if out != want {
+		t.Errorf("got ledger entry %q, want %q", out, want)
+	}
+}
+
+// TestLedgerScheduleWriterLoanRowBalances parses the rendered transaction the way hledger would - summing
+// the explicit postings and inferring the elided accountAsset amount - and checks that inferred amount
+// against the real cash movement: -payment. A sign regression here elides the wrong amount without making
+// the output look malformed, so the golden string above can't catch it on its own.
+func TestLedgerScheduleWriterLoanRowBalances(t *testing.T) {
+	currency = accounting.Accounting{Symbol: "$", Thousand: ",", Precision: 2}
+
+	w := newLedgerScheduleWriter("Liabilities:Mortgage", "Expenses:Interest:Mortgage", "Assets:Checking")
+	periodDate := time.Date(2020, time.February, 15, 0, 0, 0, 0, time.UTC)
+	payment := amortize.NewMoneyFromFloat64(17029.98)
+	if err := w.WriteRow(periodDate, "loan", amortize.NewMoneyFromFloat64(666.67), amortize.NewMoneyFromFloat64(16363.31), payment, amortize.NewMoneyFromFloat64(183636.69)); err != nil {
+		t.Fatalf("WriteRow returned error: %s", err)
+	}
+
+	out, err := w.Render()
+	if err != nil {
+		t.Fatalf("Render returned error: %s", err)
+	}
+
+	postings := parseLedgerTransaction(t, out)
+	elided, ok := postings["Assets:Checking"]
+	if !ok {
+		t.Fatalf("no Assets:Checking posting found in %q", out)
+	}
+
+	wantElided := new(big.Rat).Neg(payment.Round(2).Rat())
+	if elided.Cmp(wantElided) != 0 {
+		t.Errorf("elided Assets:Checking amount = %s, want %s (-payment, the real cash out of checking)", elided.RatString(), wantElided.RatString())
+	}
+}
+
+// TestLedgerScheduleWriterNegativePrincipal covers the remortgage-fee row a rate change can emit (a "lump
+// sum" row whose principal is negative, since it increases rather than reduces the balance).
+func TestLedgerScheduleWriterNegativePrincipal(t *testing.T) {
+	currency = accounting.Accounting{Symbol: "$", Thousand: ",", Precision: 2}
+
+	w := newLedgerScheduleWriter("Liabilities:Mortgage", "Expenses:Interest:Mortgage", "Assets:Checking")
+	periodDate := time.Date(2020, time.June, 15, 0, 0, 0, 0, time.UTC)
+	negatedFee := amortize.NewMoneyFromInt(0).Sub(amortize.NewMoneyFromFloat64(500))
+	if err := w.WriteRow(periodDate, "lump sum", amortize.NewMoneyFromInt(0), negatedFee, negatedFee, amortize.NewMoneyFromFloat64(188373)); err != nil {
+		t.Fatalf("WriteRow returned error: %s", err)
+	}
+
+	out, err := w.Render()
+	if err != nil {
+		t.Fatalf("Render returned error: %s", err)
+	}
+
+	if strings.Contains(out, "--") {
+		t.Errorf("ledger entry for a negative principal contains a malformed double-negative amount: %q", out)
+	}
+
+	want := "2020/06/15 Lump sum payment ; lump sum\n    Liabilities:Mortgage  -$500.00\n    Assets:Checking\n\n"
+	if out != want {
+		t.Errorf("got ledger entry %q, want %q", out, want)
+	}
+}
+
+// parseLedgerTransaction parses a single rendered ledger transaction - one description line followed by
+// indented postings - into account -> amount, inferring the one posting hledger would leave to elision the
+// same way hledger's balancer does: its amount is the negative of the sum of every other posting's amount.
+// This exercises the actual balancing arithmetic without requiring the hledger binary.
+func parseLedgerTransaction(t *testing.T, tx string) map[string]*big.Rat {
+	t.Helper()
+
+	lines := strings.Split(strings.TrimRight(tx, "\n"), "\n")
+	if len(lines) < 2 {
+		t.Fatalf("transaction has no postings: %q", tx)
+	}
+
+	postings := map[string]*big.Rat{}
+	elidedAccount := ""
+	sum := new(big.Rat)
+	for _, line := range lines[1:] {
+		fields := strings.SplitN(strings.TrimSpace(line), "  ", 2)
+		account := strings.TrimSpace(fields[0])
+
+		if len(fields) < 2 || strings.TrimSpace(fields[1]) == "" {
+			if elidedAccount != "" {
+				t.Fatalf("more than one posting has no amount in transaction: %q", tx)
+			}
+			elidedAccount = account
+			continue
+		}
+
+		amountText := strings.ReplaceAll(strings.ReplaceAll(fields[1], "$", ""), ",", "")
+		amount, ok := new(big.Rat).SetString(amountText)
+		if !ok {
+			t.Fatalf("posting amount %q does not parse as a number (transaction: %q)", fields[1], tx)
+		}
+
+		postings[account] = amount
+		sum.Add(sum, amount)
+	}
+
+	if elidedAccount == "" {
+		t.Fatalf("no elided posting found in transaction: %q", tx)
+	}
+	postings[elidedAccount] = new(big.Rat).Neg(sum)
+
+	return postings
+}
+
+func TestCSVScheduleWriterWritesRows(t *testing.T) {
+	currency = accounting.Accounting{Symbol: "$", Thousand: ",", Precision: 2}
+	activeLocale, _ = format.Lookup(format.DefaultLocale)
+
+	w := newCSVScheduleWriter()
+	periodDate := time.Date(2020, time.February, 15, 0, 0, 0, 0, time.UTC)
+	if err := w.WriteRow(periodDate, "loan", amortize.NewMoneyFromFloat64(666.67), amortize.NewMoneyFromFloat64(16363.31), amortize.NewMoneyFromFloat64(17029.98), amortize.NewMoneyFromFloat64(183636.69)); err != nil {
+		t.Fatalf("WriteRow returned error: %s", err)
+	}
+
+	out, err := w.Render()
+	if err != nil {
+		t.Fatalf("Render returned error: %s", err)
+	}
+
+	want := "month,type,interest,principal,payment,balance\nFebruary 15 2020,loan,$666.67,\"$16,363.31\",\"$17,029.98\",\"$183,636.69\"\n"
+	if out != want {
+		t.Errorf("got CSV %q, want %q", out, want)
+	}
+}
+
+func TestMarkdownScheduleWriterIncludesTableAndLumpSums(t *testing.T) {
+	currency = accounting.Accounting{Symbol: "$", Thousand: ",", Precision: 2}
+	activeLocale, _ = format.Lookup(format.DefaultLocale)
+
+	paymentDate := time.Date(2020, time.March, 15, 0, 0, 0, 0, time.UTC)
+	lumpSums := map[amortize.PaymentPeriod]amortize.LumpSumPayment{
+		amortize.SnapToPaymentPeriod(paymentDate, paymentDate): {PaymentDate: paymentDate, Amount: amortize.NewMoneyFromFloat64(5000)},
+	}
+
+	w := newMarkdownScheduleWriter(lumpSums)
+	if err := w.WriteRow(paymentDate, "lump sum", amortize.NewMoneyFromInt(0), amortize.NewMoneyFromFloat64(5000), amortize.NewMoneyFromFloat64(5000), amortize.NewMoneyFromFloat64(178636.69)); err != nil {
+		t.Fatalf("WriteRow returned error: %s", err)
+	}
+
+	out, err := w.Render()
+	if err != nil {
+		t.Fatalf("Render returned error: %s", err)
+	}
+
+	if !strings.Contains(out, "$5,000.00") || !strings.Contains(out, "$178,636.69") {
+		t.Errorf("rendered Markdown is missing the schedule row data: %q", out)
+	}
+	if !strings.Contains(out, "## Lump sump payments") {
+		t.Errorf("rendered Markdown is missing the lump sums section: %q", out)
+	}
+	if !strings.Contains(out, "Payment of `$5,000.00` made on `March 15 2020`") {
+		t.Errorf("rendered Markdown is missing the lump sum narrative: %q", out)
+	}
+}
+
+func TestHTMLScheduleWriterWrapsMarkdownAsHTMLPage(t *testing.T) {
+	currency = accounting.Accounting{Symbol: "$", Thousand: ",", Precision: 2}
+	activeLocale, _ = format.Lookup(format.DefaultLocale)
+
+	w := newHTMLScheduleWriter(nil)
+	periodDate := time.Date(2020, time.February, 15, 0, 0, 0, 0, time.UTC)
+	if err := w.WriteRow(periodDate, "loan", amortize.NewMoneyFromFloat64(666.67), amortize.NewMoneyFromFloat64(16363.31), amortize.NewMoneyFromFloat64(17029.98), amortize.NewMoneyFromFloat64(183636.69)); err != nil {
+		t.Fatalf("WriteRow returned error: %s", err)
+	}
+
+	out, err := w.Render()
+	if err != nil {
+		t.Fatalf("Render returned error: %s", err)
+	}
+
+	if !strings.Contains(out, "<html") {
+		t.Errorf("rendered HTML is missing the page wrapper: %q", out)
+	}
+	if !strings.Contains(out, "16,363.31") {
+		t.Errorf("rendered HTML is missing the schedule row data: %q", out)
+	}
+}
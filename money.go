@@ -0,0 +1,12 @@
+package main
+
+import (
+	"github.com/alexandre-normand/wallace/pkg/amortize"
+	"github.com/leekchan/accounting"
+)
+
+// FormatMoney formats m using the given accounting.Accounting (symbol, separators and precision), rounding
+// to the formatter's configured precision in the process.
+func FormatMoney(a accounting.Accounting, m amortize.Money) string {
+	return a.FormatMoneyBigRat(m.Rat())
+}
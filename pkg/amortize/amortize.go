@@ -0,0 +1,119 @@
+package amortize
+
+import (
+	"fmt"
+	"time"
+)
+
+// LumpSumPayment is a one-time prepayment applied in addition to a period's regular loan payment. Currency,
+// ExchangeRate, ExchangeRateDate and OriginalPaymentValue are only set when the prepayment was read out of a
+// foreign-currency bank export and converted to the loan's own currency.
+type LumpSumPayment struct {
+	PaymentPeriod
+	PaymentDate          time.Time
+	Amount               Money
+	Currency             *string
+	ExchangeRate         *Money
+	ExchangeRateDate     *time.Time
+	OriginalPaymentValue *Money
+}
+
+// ByPaymentDate sorts a slice of LumpSumPayment by PaymentDate, ascending.
+type ByPaymentDate []LumpSumPayment
+
+func (a ByPaymentDate) Len() int           { return len(a) }
+func (a ByPaymentDate) Less(i, j int) bool { return a[i].PaymentDate.Before(a[j].PaymentDate) }
+func (a ByPaymentDate) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+
+// RateChange is one step in a rate schedule: the new annual interest rate effective from EffectiveDate
+// onward, and an optional one-time fee (e.g. for a renewal or remortgage) added to the balance when the
+// change takes effect.
+type RateChange struct {
+	EffectiveDate time.Time
+	AnnualRate    Money
+	RemortgageFee Money
+}
+
+// Row is a single line of a rendered amortization schedule: a regular "loan" payment, a "lump sum"
+// prepayment, or a "rate change".
+type Row struct {
+	Date      time.Time
+	Type      string
+	Interest  Money
+	Principal Money
+	Payment   Money
+	Balance   Money
+}
+
+// Schedule is the ordered list of rows produced by Amortize.
+type Schedule []Row
+
+// Params is the input to Amortize: everything needed to compute a full amortization schedule.
+type Params struct {
+	LoanAmount Money
+	StartDate  time.Time
+	// AnnualInterest is a fraction, e.g. 0.04 for 4%.
+	AnnualInterest Money
+	Years          int
+	LumpSums       map[PaymentPeriod]LumpSumPayment
+	RateChanges    map[PaymentPeriod]RateChange
+}
+
+// Amortize computes the full amortization schedule for params: the level monthly payment recast at every
+// rate change, interest/principal/balance for each period, and lump sum and rate change rows interleaved at
+// the periods they apply to.
+func Amortize(params Params) (Schedule, error) {
+	paymentCount := getPaymentCount(params.Years)
+	monthlyInterest := params.AnnualInterest.Quo(NewMoneyFromInt(12))
+	monthlyPayment := getMonthlyPayment(monthlyInterest, params.LoanAmount, paymentCount)
+
+	zeroMoney := NewMoneyFromInt(0)
+	balance := params.LoanAmount.Round(2)
+
+	var schedule Schedule
+
+	for n := 0; n <= paymentCount && balance.Cmp(zeroMoney) > 0; n++ {
+		periodDate := params.StartDate.AddDate(0, n, 0)
+
+		if n > 0 {
+			if change, ok := params.RateChanges[SnapToPaymentPeriod(periodDate, params.StartDate)]; ok {
+				monthlyInterest = change.AnnualRate.Quo(NewMoneyFromInt(12))
+				monthlyPayment = getMonthlyPayment(monthlyInterest, balance, paymentCount-n+1)
+
+				schedule = append(schedule, Row{Date: periodDate, Type: "rate change", Interest: zeroMoney, Principal: zeroMoney, Payment: monthlyPayment, Balance: balance})
+
+				if change.RemortgageFee.Cmp(zeroMoney) != 0 {
+					balance = balance.Add(change.RemortgageFee).Round(2)
+					negatedFee := zeroMoney.Sub(change.RemortgageFee)
+					schedule = append(schedule, Row{Date: periodDate, Type: "lump sum", Interest: zeroMoney, Principal: negatedFee, Payment: negatedFee, Balance: balance})
+				}
+			}
+		}
+
+		monthInterest := zeroMoney
+		monthPrincipal := zeroMoney
+		monthPayment := zeroMoney
+		if n > 0 {
+			monthInterest = getInterest(balance, monthlyInterest).Round(2)
+			// The month's principal is either the monthly payment minus the interest or the remaining balance if we're
+			// at the last payment
+			monthPrincipal = monthlyPayment.Sub(monthInterest).Min(balance).Round(2)
+			balance = balance.Sub(monthPrincipal).Round(2)
+			monthPayment = monthlyPayment.Min(monthInterest.Add(monthPrincipal))
+		}
+
+		schedule = append(schedule, Row{Date: periodDate, Type: "loan", Interest: monthInterest, Principal: monthPrincipal, Payment: monthPayment, Balance: balance})
+
+		if payment, ok := params.LumpSums[PaymentPeriod{Month: periodDate.Month(), Year: periodDate.Year(), Day: periodDate.Day()}]; ok {
+			daysSinceLastPayment := int(payment.PaymentDate.Sub(periodDate).Hours()) / 24
+			if daysSinceLastPayment > 0 {
+				return nil, fmt.Errorf("lump sum payments are only supported when made on the same date as the montly loan payments but had a payment on day [%s] with loan payment date of [%s]", payment.PaymentDate, periodDate)
+			}
+
+			balance = balance.Sub(payment.Amount)
+			schedule = append(schedule, Row{Date: payment.PaymentDate, Type: "lump sum", Interest: zeroMoney, Principal: payment.Amount, Payment: payment.Amount, Balance: balance})
+		}
+	}
+
+	return schedule, nil
+}
@@ -0,0 +1,179 @@
+package amortize
+
+import (
+	"testing"
+	"time"
+)
+
+func thirtyYearParams() Params {
+	return Params{
+		LoanAmount:     NewMoneyFromFloat64(200000),
+		StartDate:      time.Date(2020, time.January, 15, 0, 0, 0, 0, time.UTC),
+		AnnualInterest: NewMoneyFromFloat64(4).Quo(NewMoneyFromInt(100)),
+		Years:          30,
+	}
+}
+
+// TestAmortizeSumsToLoanAmount asserts that, over a full term with no lump sums or rate changes, the sum of
+// every row's principal plus the final balance is exactly the loan amount, to the cent - the invariant exact
+// big.Rat arithmetic exists to guarantee over a 30-year schedule.
+func TestAmortizeSumsToLoanAmount(t *testing.T) {
+	params := thirtyYearParams()
+
+	schedule, err := Amortize(params)
+	if err != nil {
+		t.Fatalf("Amortize returned error: %s", err)
+	}
+
+	principalSum := NewMoneyFromInt(0)
+	var finalBalance Money
+	for _, row := range schedule {
+		if row.Type != "loan" {
+			continue
+		}
+		principalSum = principalSum.Add(row.Principal)
+		finalBalance = row.Balance
+	}
+
+	total := principalSum.Add(finalBalance)
+	if total.Cmp(params.LoanAmount) != 0 {
+		t.Errorf("sum of principal (%s) + final balance (%s) = %s, want loan amount %s", principalSum, finalBalance, total, params.LoanAmount)
+	}
+}
+
+// TestAmortizeInterestPlusPrincipalEqualsPaymentForNonFinalRows asserts that, for every "loan" row but the
+// first (the disbursement) and the last (which may be short), interest + principal == payment exactly.
+func TestAmortizeInterestPlusPrincipalEqualsPaymentForNonFinalRows(t *testing.T) {
+	params := thirtyYearParams()
+
+	schedule, err := Amortize(params)
+	if err != nil {
+		t.Fatalf("Amortize returned error: %s", err)
+	}
+
+	var loanRows []Row
+	for _, row := range schedule {
+		if row.Type == "loan" {
+			loanRows = append(loanRows, row)
+		}
+	}
+
+	for i, row := range loanRows {
+		if i == 0 || i == len(loanRows)-1 {
+			continue
+		}
+		if row.Interest.Add(row.Principal).Cmp(row.Payment) != 0 {
+			t.Errorf("row %d (%s): interest (%s) + principal (%s) != payment (%s)", i, row.Date.Format("2006-01-02"), row.Interest, row.Principal, row.Payment)
+		}
+	}
+}
+
+// TestAmortizeARMWithTwoStepUps covers a 5/1 ARM: a 30-year loan that starts at 4%, then steps up to 5% at
+// the 5-year mark and 5.5% a year after that. Each step-up must recast the payment over the remaining
+// balance and term, and the principal paid plus the final balance must still add up to the loan amount to
+// the exact cent despite the mid-term recasts.
+func TestAmortizeARMWithTwoStepUps(t *testing.T) {
+	startDate := time.Date(2020, time.January, 15, 0, 0, 0, 0, time.UTC)
+	firstStepUp := startDate.AddDate(5, 0, 0)
+	secondStepUp := startDate.AddDate(6, 0, 0)
+
+	params := Params{
+		LoanAmount:     NewMoneyFromFloat64(200000),
+		StartDate:      startDate,
+		AnnualInterest: NewMoneyFromFloat64(4).Quo(NewMoneyFromInt(100)),
+		Years:          30,
+		RateChanges: map[PaymentPeriod]RateChange{
+			SnapToPaymentPeriod(firstStepUp, startDate):  {EffectiveDate: firstStepUp, AnnualRate: NewMoneyFromFloat64(5).Quo(NewMoneyFromInt(100)), RemortgageFee: NewMoneyFromInt(0)},
+			SnapToPaymentPeriod(secondStepUp, startDate): {EffectiveDate: secondStepUp, AnnualRate: NewMoneyFromFloat64(5.5).Quo(NewMoneyFromInt(100)), RemortgageFee: NewMoneyFromInt(0)},
+		},
+	}
+
+	schedule, err := Amortize(params)
+	if err != nil {
+		t.Fatalf("Amortize returned error: %s", err)
+	}
+
+	var rateChangeRows []Row
+	principalSum := NewMoneyFromInt(0)
+	var finalBalance Money
+	for _, row := range schedule {
+		if row.Type == "rate change" {
+			rateChangeRows = append(rateChangeRows, row)
+		}
+		if row.Type == "loan" {
+			principalSum = principalSum.Add(row.Principal)
+			finalBalance = row.Balance
+		}
+	}
+
+	if len(rateChangeRows) != 2 {
+		t.Fatalf("got %d rate change rows, want 2", len(rateChangeRows))
+	}
+	if !rateChangeRows[0].Date.Equal(firstStepUp) {
+		t.Errorf("first rate change date = %s, want %s", rateChangeRows[0].Date, firstStepUp)
+	}
+	if !rateChangeRows[1].Date.Equal(secondStepUp) {
+		t.Errorf("second rate change date = %s, want %s", rateChangeRows[1].Date, secondStepUp)
+	}
+	if rateChangeRows[0].Payment.Cmp(rateChangeRows[1].Payment) == 0 {
+		t.Errorf("payment should be recast by each step-up, but both rate change rows carry payment %s", rateChangeRows[0].Payment)
+	}
+
+	total := principalSum.Add(finalBalance)
+	if total.Cmp(params.LoanAmount) != 0 {
+		t.Errorf("sum of principal (%s) + final balance (%s) = %s, want loan amount %s", principalSum, finalBalance, total, params.LoanAmount)
+	}
+}
+
+// TestAmortizeCanadianStyleRenewal covers a Canadian-style 5-year renewal: a single rate change at the
+// 5-year mark that also rolls a remortgage fee into the balance as a synthetic negative lump sum. The fee
+// must land as its own "lump sum" row, right after the "rate change" row, and increase the balance rather
+// than reduce it.
+func TestAmortizeCanadianStyleRenewal(t *testing.T) {
+	startDate := time.Date(2020, time.January, 15, 0, 0, 0, 0, time.UTC)
+	renewal := startDate.AddDate(5, 0, 0)
+	fee := NewMoneyFromFloat64(1500)
+
+	params := Params{
+		LoanAmount:     NewMoneyFromFloat64(200000),
+		StartDate:      startDate,
+		AnnualInterest: NewMoneyFromFloat64(5).Quo(NewMoneyFromInt(100)),
+		Years:          25,
+		RateChanges: map[PaymentPeriod]RateChange{
+			SnapToPaymentPeriod(renewal, startDate): {EffectiveDate: renewal, AnnualRate: NewMoneyFromFloat64(5.75).Quo(NewMoneyFromInt(100)), RemortgageFee: fee},
+		},
+	}
+
+	schedule, err := Amortize(params)
+	if err != nil {
+		t.Fatalf("Amortize returned error: %s", err)
+	}
+
+	var rateChangeIndex = -1
+	for i, row := range schedule {
+		if row.Type == "rate change" && row.Date.Equal(renewal) {
+			rateChangeIndex = i
+			break
+		}
+	}
+	if rateChangeIndex == -1 {
+		t.Fatalf("no rate change row found at renewal date %s", renewal)
+	}
+	if rateChangeIndex+1 >= len(schedule) {
+		t.Fatalf("rate change row at index %d has no following row for the remortgage fee", rateChangeIndex)
+	}
+
+	balanceBeforeFee := schedule[rateChangeIndex].Balance
+
+	feeRow := schedule[rateChangeIndex+1]
+	if feeRow.Type != "lump sum" {
+		t.Fatalf("row following the rate change is %q, want %q", feeRow.Type, "lump sum")
+	}
+	negatedFee := NewMoneyFromInt(0).Sub(fee)
+	if feeRow.Principal.Cmp(negatedFee) != 0 {
+		t.Errorf("remortgage fee row principal = %s, want %s (a synthetic negative lump sum)", feeRow.Principal, negatedFee)
+	}
+	if feeRow.Balance.Cmp(balanceBeforeFee.Add(fee)) != 0 {
+		t.Errorf("balance after the remortgage fee = %s, want %s (balance before the fee + fee)", feeRow.Balance, balanceBeforeFee.Add(fee))
+	}
+}
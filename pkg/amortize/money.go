@@ -0,0 +1,173 @@
+// Package amortize computes a mortgage amortization schedule: level monthly payments, lump sum prepayments,
+// and mid-term rate changes, all in exact rational arithmetic. It is used by both wallace's CLI and its
+// `serve` HTTP mode so the two share one implementation of the actual math.
+package amortize
+
+import (
+	"math/big"
+
+	bigfloat "github.com/ALTree/floatutils"
+)
+
+// maxAnnuityDenominator bounds the denominator used when approximating the annuity factor (computed via
+// bigfloat.Pow, which only operates on big.Float) as a big.Rat, so the resulting Money stays a small,
+// well-behaved fraction instead of the astronomically large exact rational a raw float-to-Rat conversion
+// would produce.
+const maxAnnuityDenominator = 1000000000000
+
+// Money is an exact monetary amount backed by a big.Rat. Unlike big.Float, it carries no binary rounding
+// error, so a schedule's rows sum back to the loan amount to the cent over a 30-year term.
+type Money struct {
+	rat *big.Rat
+}
+
+// NewMoney wraps an existing big.Rat as a Money. The Money takes ownership of r; callers should not mutate
+// it afterwards.
+func NewMoney(r *big.Rat) Money {
+	return Money{rat: r}
+}
+
+// NewMoneyFromInt returns the exact Money value of n.
+func NewMoneyFromInt(n int64) Money {
+	return Money{rat: new(big.Rat).SetInt64(n)}
+}
+
+// NewMoneyFromFloat64 returns the Money value of f. f is typically a command line flag or a JSON field, so
+// this is exact only to the precision float64 itself carries.
+func NewMoneyFromFloat64(f float64) Money {
+	return Money{rat: new(big.Rat).SetFloat64(f)}
+}
+
+// Rat returns the underlying big.Rat. Callers should not mutate the result.
+func (m Money) Rat() *big.Rat {
+	return m.rat
+}
+
+// Add returns m + o.
+func (m Money) Add(o Money) Money {
+	return Money{rat: new(big.Rat).Add(m.rat, o.rat)}
+}
+
+// Sub returns m - o.
+func (m Money) Sub(o Money) Money {
+	return Money{rat: new(big.Rat).Sub(m.rat, o.rat)}
+}
+
+// Mul returns m * o.
+func (m Money) Mul(o Money) Money {
+	return Money{rat: new(big.Rat).Mul(m.rat, o.rat)}
+}
+
+// Quo returns m / o.
+func (m Money) Quo(o Money) Money {
+	return Money{rat: new(big.Rat).Quo(m.rat, o.rat)}
+}
+
+// Cmp compares m and o, returning -1, 0 or +1 as m is less than, equal to, or greater than o.
+func (m Money) Cmp(o Money) int {
+	return m.rat.Cmp(o.rat)
+}
+
+// Min returns the lesser of m and o.
+func (m Money) Min(o Money) Money {
+	if m.Cmp(o) < 0 {
+		return m
+	}
+	return o
+}
+
+// String returns a fixed-precision decimal representation of m, for diagnostics; use FormatMoney for
+// user-facing currency display.
+func (m Money) String() string {
+	return m.rat.FloatString(6)
+}
+
+// Round rounds m to the given number of decimals using round-half-to-even (banker's rounding).
+func (m Money) Round(decimals int) Money {
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	scaled := new(big.Rat).Mul(m.rat, new(big.Rat).SetInt(scale))
+
+	neg := scaled.Sign() < 0
+	if neg {
+		scaled.Neg(scaled)
+	}
+
+	num, denom := scaled.Num(), scaled.Denom()
+	q, r := new(big.Int).QuoRem(num, denom, new(big.Int))
+
+	doubledRemainder := new(big.Int).Lsh(r, 1)
+	switch doubledRemainder.Cmp(denom) {
+	case 1:
+		q.Add(q, big.NewInt(1))
+	case 0:
+		if q.Bit(0) == 1 {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+
+	rounded := new(big.Rat).SetFrac(q, scale)
+	if neg {
+		rounded.Neg(rounded)
+	}
+
+	return Money{rat: rounded}
+}
+
+// ratFromFloat approximates f as a big.Rat via its continued fraction expansion, stopping once the
+// denominator of the next convergent would exceed maxDenominator.
+func ratFromFloat(f *big.Float, maxDenominator int64) *big.Rat {
+	maxDenom := big.NewInt(maxDenominator)
+
+	x := new(big.Float).Copy(f)
+	h0, h1 := big.NewInt(0), big.NewInt(1)
+	k0, k1 := big.NewInt(1), big.NewInt(0)
+
+	for i := 0; i < 64; i++ {
+		a, _ := x.Int(nil)
+		aFloat := new(big.Float).SetPrec(x.Prec()).SetInt(a)
+
+		h2 := new(big.Int).Add(new(big.Int).Mul(a, h1), h0)
+		k2 := new(big.Int).Add(new(big.Int).Mul(a, k1), k0)
+		if k2.CmpAbs(maxDenom) > 0 {
+			break
+		}
+		h0, h1 = h1, h2
+		k0, k1 = k1, k2
+
+		frac := new(big.Float).Sub(x, aFloat)
+		if frac.Sign() == 0 {
+			break
+		}
+		x = new(big.Float).Quo(big.NewFloat(1).SetPrec(x.Prec()), frac)
+	}
+
+	if k1.Sign() == 0 {
+		k1 = big.NewInt(1)
+	}
+
+	return new(big.Rat).SetFrac(h1, k1)
+}
+
+// getMonthlyPayment computes the level monthly payment for an annuity: monthlyRate / (1 -
+// (1+monthlyRate)^-paymentCount) * loanAmount. The exponentiation has no exact rational closed form, so it's
+// the one step still done in big.Float (via bigfloat.Pow); the result is immediately approximated back to a
+// bounded-denominator big.Rat so the rest of the computation stays exact.
+func getMonthlyPayment(monthlyRate Money, loanAmount Money, paymentCount int) Money {
+	one := NewMoneyFromInt(1)
+
+	base := new(big.Float).SetPrec(200).SetRat(one.Add(monthlyRate).Rat())
+	negPaymentCount := big.NewFloat(float64(-paymentCount))
+	pow := bigfloat.Pow(base, negPaymentCount)
+	powMoney := NewMoney(ratFromFloat(pow, maxAnnuityDenominator))
+
+	divisor := one.Sub(powMoney)
+	return monthlyRate.Quo(divisor).Mul(loanAmount)
+}
+
+func getInterest(principal Money, monthlyRate Money) Money {
+	return principal.Mul(monthlyRate)
+}
+
+func getPaymentCount(years int) int {
+	return years * 12
+}
@@ -0,0 +1,23 @@
+package amortize
+
+import "time"
+
+// PaymentPeriod holds the start date of a payment period.
+type PaymentPeriod struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+// SnapToPaymentPeriod maps date to the PaymentPeriod of the loan payment it falls into, given the loan's
+// startDate: dates on or after startDate's day-of-month belong to that calendar month's period, and earlier
+// ones belong to the previous month's (since the loan payment for that period hasn't happened yet).
+func SnapToPaymentPeriod(date time.Time, startDate time.Time) PaymentPeriod {
+	if date.Day() < startDate.Day() {
+		paymentMonth := date.AddDate(0, -1, 0)
+		paymentDay := time.Date(paymentMonth.Year(), paymentMonth.Month(), startDate.Day(), 0, 0, 0, 0, time.UTC)
+		return PaymentPeriod{Month: paymentDay.Month(), Year: paymentDay.Year(), Day: paymentDay.Day()}
+	}
+
+	return PaymentPeriod{Month: date.Month(), Year: date.Year(), Day: startDate.Day()}
+}
@@ -0,0 +1,270 @@
+// Package prepayments reads lump sum loan prepayments out of the file formats banks actually hand users:
+// wallace's own lump sums CSV, and OFX/QFX statement exports.
+package prepayments
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Entry is a single prepayment record read from a bank export, before it is snapped to a loan's payment
+// period. Amounts are exact big.Rat values parsed straight out of their decimal representation, with no
+// binary floating point round trip.
+type Entry struct {
+	PaymentDate      time.Time
+	Amount           *big.Rat
+	OriginalAmount   *big.Rat
+	Currency         *string
+	ExchangeRate     *big.Rat
+	ExchangeRateDate *time.Time
+}
+
+// Parser reads prepayment entries out of a bank export.
+type Parser interface {
+	Parse(r io.Reader) ([]Entry, error)
+}
+
+// IsOFX reports whether a prepayments file looks like an OFX/QFX export, by extension or, failing that, by
+// sniffing its content.
+func IsOFX(filename string, content []byte) bool {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".ofx", ".qfx":
+		return true
+	}
+
+	trimmed := bytes.ToUpper(bytes.TrimSpace(content))
+	sniffLen := len(trimmed)
+	if sniffLen > 512 {
+		sniffLen = 512
+	}
+
+	return bytes.HasPrefix(trimmed, []byte("OFXHEADER")) || bytes.Contains(trimmed[:sniffLen], []byte("<OFX>"))
+}
+
+// CSVParser reads wallace's own lump sums CSV format:
+// paymentTime,paymentValue[,originalValue,originalCurrency,exchangeRate,exchangeRateDate].
+type CSVParser struct {
+	// DateLayout is the time.Parse layout used for the paymentTime and exchangeRateDate columns.
+	DateLayout string
+
+	// ParseDate parses a DateLayout-formatted date. If nil, defaults to time.ParseInLocation(DateLayout,
+	// value, time.UTC); callers that need locale-aware month names supply their own.
+	ParseDate func(layout, value string) (time.Time, error)
+}
+
+func (p CSVParser) parseDate(value string) (time.Time, error) {
+	if p.ParseDate != nil {
+		return p.ParseDate(p.DateLayout, value)
+	}
+	return time.ParseInLocation(p.DateLayout, value, time.UTC)
+}
+
+func (p CSVParser) Parse(r io.Reader) ([]Entry, error) {
+	cr := csv.NewReader(r)
+
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(records))
+	for line, record := range records {
+		if len(record) < 2 {
+			return nil, fmt.Errorf("Incorrect format, should be: paymentTime,paymentValue or paymentTime,paymentValue,originalValue,originalCurrency,exchangeRate,exchangeRateDate but was %v", record)
+		}
+
+		paymentDate, err := p.parseDate(record[0])
+		if err != nil {
+			if line == 0 {
+				continue
+			}
+			return nil, errors.Wrapf(err, "Error reading payment time at line %d, should be in format %s", line, p.DateLayout)
+		}
+
+		amount, ok := new(big.Rat).SetString(record[1])
+		if !ok {
+			if line == 0 {
+				continue
+			}
+			return nil, fmt.Errorf("Error reading payment value at line %d", line)
+		}
+
+		entry := Entry{PaymentDate: paymentDate, Amount: amount}
+
+		if len(record) >= 3 {
+			entry.OriginalAmount, ok = new(big.Rat).SetString(record[2])
+			if !ok {
+				return nil, fmt.Errorf("failure to read originalPaymentValue line %d", line)
+			}
+		}
+
+		if len(record) >= 4 {
+			entry.Currency = &record[3]
+		}
+
+		if len(record) >= 5 {
+			entry.ExchangeRate, ok = new(big.Rat).SetString(record[4])
+			if !ok {
+				return nil, fmt.Errorf("failure to read exchangeRate line %d", line)
+			}
+		}
+
+		if len(record) >= 6 {
+			d, err := p.parseDate(record[5])
+			if err != nil {
+				return nil, fmt.Errorf("failure to read exchangeRateDate at line %d, should be in format %s: %w", line, p.DateLayout, err)
+			}
+			entry.ExchangeRateDate = &d
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// OFXParser reads prepayments out of an OFX/QFX bank statement export, one Entry per <STMTTRN> whose NAME or
+// MEMO contains PayeeFilter (case-insensitive). An empty PayeeFilter matches every transaction.
+type OFXParser struct {
+	PayeeFilter string
+}
+
+var stmttrnTag = regexp.MustCompile(`(?i)<STMTTRN>`)
+
+func (p OFXParser) Parse(r io.Reader) ([]Entry, error) {
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	locs := stmttrnTag.FindAllIndex(content, -1)
+	entries := make([]Entry, 0, len(locs))
+
+	for i, loc := range locs {
+		start := loc[1]
+		end := len(content)
+		if i+1 < len(locs) {
+			end = locs[i+1][0]
+		}
+		block := string(content[start:end])
+
+		name := tagValue(block, "NAME")
+		memo := tagValue(block, "MEMO")
+		if p.PayeeFilter != "" && !strings.Contains(strings.ToUpper(name+" "+memo), strings.ToUpper(p.PayeeFilter)) {
+			continue
+		}
+
+		dtposted := tagValue(block, "DTPOSTED")
+		if len(dtposted) < 8 {
+			return nil, fmt.Errorf("invalid or missing DTPOSTED in STMTTRN block: %q", block)
+		}
+		paymentDate, err := time.ParseInLocation("20060102", dtposted[:8], time.UTC)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error reading DTPOSTED %q", dtposted)
+		}
+
+		trnamt := tagValue(block, "TRNAMT")
+		amount, ok := new(big.Rat).SetString(trnamt)
+		if !ok {
+			return nil, fmt.Errorf("error reading TRNAMT %q", trnamt)
+		}
+		amount.Abs(amount)
+
+		entry := Entry{PaymentDate: paymentDate, Amount: amount}
+
+		if currencyBlock := subBlock(block, "ORIGCURRENCY"); currencyBlock != "" {
+			entry = withOrigCurrencyBlock(entry, currencyBlock, paymentDate)
+		} else if currencyBlock := subBlock(block, "CURRENCY"); currencyBlock != "" {
+			entry = withCurrencyBlock(entry, currencyBlock, paymentDate)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// withOrigCurrencyBlock populates the multi-currency fields of entry from an OFX ORIGCURRENCY aggregate, in
+// which TRNAMT (already parsed into entry.Amount) is in the foreign currency CURSYM and has NOT been
+// converted to the account's home currency. CURRATE is the ratio of home currency to CURSYM ("the ratio of
+// <CURDEF> currency to <CURSYM> currency", per the OFX spec), so the home amount is the foreign amount times
+// CURRATE - matching the "originalValue x exchangeRate = amount" convention documented in the Markdown
+// lump sum narrative.
+func withOrigCurrencyBlock(entry Entry, block string, paymentDate time.Time) Entry {
+	cursym, rate, ok := currencyRate(block)
+	if !ok {
+		return entry
+	}
+
+	entry.OriginalAmount = new(big.Rat).Set(entry.Amount)
+	entry.Currency = &cursym
+	entry.ExchangeRate = rate
+	exchangeRateDate := paymentDate
+	entry.ExchangeRateDate = &exchangeRateDate
+	entry.Amount = new(big.Rat).Mul(entry.Amount, rate)
+
+	return entry
+}
+
+// withCurrencyBlock populates the multi-currency fields of entry from an OFX CURRENCY aggregate, in which
+// TRNAMT (already parsed into entry.Amount) is already converted into the account's home currency. The
+// foreign amount is derived from it so that, as with ORIGCURRENCY, "originalValue x exchangeRate = amount"
+// holds.
+func withCurrencyBlock(entry Entry, block string, paymentDate time.Time) Entry {
+	cursym, rate, ok := currencyRate(block)
+	if !ok {
+		return entry
+	}
+
+	entry.OriginalAmount = new(big.Rat).Quo(entry.Amount, rate)
+	entry.Currency = &cursym
+	entry.ExchangeRate = rate
+	exchangeRateDate := paymentDate
+	entry.ExchangeRateDate = &exchangeRateDate
+
+	return entry
+}
+
+// currencyRate extracts CURSYM and CURRATE from a CURRENCY/ORIGCURRENCY aggregate.
+func currencyRate(block string) (cursym string, rate *big.Rat, ok bool) {
+	cursym = tagValue(block, "CURSYM")
+	currate := tagValue(block, "CURRATE")
+	if cursym == "" || currate == "" {
+		return "", nil, false
+	}
+
+	rate, ok = new(big.Rat).SetString(currate)
+	if !ok {
+		return "", nil, false
+	}
+
+	return cursym, rate, true
+}
+
+func subBlock(block, tag string) string {
+	re := regexp.MustCompile(`(?is)<` + tag + `>(.*?)</` + tag + `>`)
+	m := re.FindStringSubmatch(block)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+func tagValue(block, tag string) string {
+	re := regexp.MustCompile(`(?i)<` + tag + `>([^<\r\n]*)`)
+	m := re.FindStringSubmatch(block)
+	if m == nil {
+		return ""
+	}
+	return strings.TrimSpace(m[1])
+}
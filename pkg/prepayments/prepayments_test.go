@@ -0,0 +1,78 @@
+package prepayments
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// TestOFXParserOrigCurrency pins down the ORIGCURRENCY direction: TRNAMT is in the foreign currency and has
+// NOT been converted, so the home currency amount is TRNAMT x CURRATE.
+func TestOFXParserOrigCurrency(t *testing.T) {
+	ofx := `<OFXHEADER>100</OFXHEADER>
+<OFX>
+<STMTTRN>
+<DTPOSTED>20200615
+<TRNAMT>-100.00
+<NAME>EXTRA PRINCIPAL PAYMENT
+<MEMO>mortgage prepay
+<ORIGCURRENCY>
+<CURRATE>1.25
+<CURSYM>EUR
+</ORIGCURRENCY>
+</STMTTRN>
+</OFX>`
+
+	entries, err := OFXParser{}.Parse(strings.NewReader(ofx))
+	if err != nil {
+		t.Fatalf("Parse returned error: %s", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+
+	entry := entries[0]
+	if entry.OriginalAmount.Cmp(big.NewRat(100, 1)) != 0 {
+		t.Errorf("got OriginalAmount %s, want 100 (the unconverted foreign TRNAMT)", entry.OriginalAmount.RatString())
+	}
+	if entry.Amount.Cmp(big.NewRat(125, 1)) != 0 {
+		t.Errorf("got Amount %s, want 125 (100 EUR x 1.25 CURRATE)", entry.Amount.RatString())
+	}
+	if entry.Currency == nil || *entry.Currency != "EUR" {
+		t.Errorf("got Currency %v, want EUR", entry.Currency)
+	}
+}
+
+// TestOFXParserCurrency pins down the CURRENCY direction: TRNAMT is already in the home currency, so the
+// foreign OriginalAmount is derived as TRNAMT / CURRATE.
+func TestOFXParserCurrency(t *testing.T) {
+	ofx := `<OFXHEADER>100</OFXHEADER>
+<OFX>
+<STMTTRN>
+<DTPOSTED>20200615
+<TRNAMT>-125.00
+<NAME>EXTRA PRINCIPAL PAYMENT
+<MEMO>mortgage prepay
+<CURRENCY>
+<CURRATE>1.25
+<CURSYM>EUR
+</CURRENCY>
+</STMTTRN>
+</OFX>`
+
+	entries, err := OFXParser{}.Parse(strings.NewReader(ofx))
+	if err != nil {
+		t.Fatalf("Parse returned error: %s", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+
+	entry := entries[0]
+	if entry.Amount.Cmp(big.NewRat(125, 1)) != 0 {
+		t.Errorf("got Amount %s, want 125 (TRNAMT is already the home currency amount)", entry.Amount.RatString())
+	}
+	if entry.OriginalAmount.Cmp(big.NewRat(100, 1)) != 0 {
+		t.Errorf("got OriginalAmount %s, want 100 (125 / 1.25 CURRATE)", entry.OriginalAmount.RatString())
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/alexandre-normand/wallace/pkg/amortize"
+)
+
+// getRateSchedule reads an optional --rateSchedule CSV (effectiveDate,annualRate[,remortgageFeeAmount]) into
+// a map of PaymentPeriod to RateChange, snapped to the loan's payment periods the same way getLumpSums snaps
+// prepayments. rateScheduleFile may be nil, in which case the loan's --interest rate never changes.
+func getRateSchedule(verboseLog *log.Logger, rateScheduleFile *os.File, startDate time.Time) (map[amortize.PaymentPeriod]amortize.RateChange, error) {
+	changes := make(map[amortize.PaymentPeriod]amortize.RateChange)
+	if rateScheduleFile == nil {
+		return changes, nil
+	}
+
+	r := csv.NewReader(rateScheduleFile)
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	for line, record := range records {
+		if len(record) < 2 {
+			if line == 0 {
+				continue
+			}
+			return nil, fmt.Errorf("Incorrect format, should be: effectiveDate,annualRate[,remortgageFeeAmount] but was %v", record)
+		}
+
+		effectiveDate, err := activeLocale.ParseDate(paymentTimeFormat, record[0])
+		if err != nil {
+			if line == 0 {
+				continue
+			}
+			return nil, fmt.Errorf("Error reading effectiveDate at line %d, should be in format %s: %s", line, paymentTimeFormat, err.Error())
+		}
+
+		rateValue, ok := new(big.Rat).SetString(record[1])
+		if !ok {
+			if line == 0 {
+				continue
+			}
+			return nil, fmt.Errorf("Error reading annualRate at line %d", line)
+		}
+
+		change := amortize.RateChange{EffectiveDate: effectiveDate, AnnualRate: amortize.NewMoney(rateValue).Quo(amortize.NewMoneyFromInt(100)), RemortgageFee: amortize.NewMoneyFromInt(0)}
+		if len(record) >= 3 && record[2] != "" {
+			feeValue, ok := new(big.Rat).SetString(record[2])
+			if !ok {
+				return nil, fmt.Errorf("Error reading remortgageFeeAmount at line %d", line)
+			}
+			change.RemortgageFee = amortize.NewMoney(feeValue)
+		}
+
+		pp := amortize.SnapToPaymentPeriod(effectiveDate, startDate)
+		if _, ok := changes[pp]; ok {
+			return nil, fmt.Errorf("only one rate change per period supported but got multiple for period [%v]", pp)
+		}
+
+		verboseLog.Printf("Rate change to %s%% effective %s", change.AnnualRate.Mul(amortize.NewMoneyFromInt(100)), effectiveDate.Format(paymentTimeFormat))
+		changes[pp] = change
+	}
+
+	return changes, nil
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/alexandre-normand/wallace/internal/format"
+	"github.com/alexandre-normand/wallace/pkg/amortize"
+)
+
+// runServe boots wallace as an HTTP server: GET / serves an interactive form that recomputes the schedule as
+// its inputs change, and POST /schedule computes a schedule from a JSON request and returns its rows as JSON.
+// The server always formats amounts and dates using the default locale; --locale/--currency are CLI-only.
+func runServe(addr string) {
+	activeLocale, _ = format.Lookup(format.DefaultLocale)
+	currency = activeLocale.NewAccounting(format.CurrencySymbol("USD"), 2)
+	exchangeRate = activeLocale.NewAccounting("", 4)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", handleIndex)
+	mux.HandleFunc("/schedule", handleSchedule)
+
+	log.Printf("wallace serve listening on %s", addr)
+	log.Fatal(http.ListenAndServe(addr, mux))
+}
+
+// scheduleLumpSumRequest is a lump sum entry in a POST /schedule request body.
+type scheduleLumpSumRequest struct {
+	Date   string  `json:"date"`
+	Amount float64 `json:"amount"`
+}
+
+// scheduleRequest is the body of a POST /schedule request.
+type scheduleRequest struct {
+	LoanAmount float64                  `json:"loanAmount"`
+	StartDate  string                   `json:"startDate"`
+	Interest   float64                  `json:"interest"`
+	Years      int                      `json:"years"`
+	LumpSums   []scheduleLumpSumRequest `json:"lumpSums"`
+}
+
+// scheduleRowResponse is a single row of a POST /schedule JSON response, with amounts and dates already
+// formatted for display.
+type scheduleRowResponse struct {
+	Date      string `json:"date"`
+	Type      string `json:"type"`
+	Interest  string `json:"interest"`
+	Principal string `json:"principal"`
+	Payment   string `json:"payment"`
+	Balance   string `json:"balance"`
+}
+
+func handleSchedule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST required", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req scheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request body: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	startDate, err := activeLocale.ParseDate(paymentTimeFormat, req.StartDate)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid startDate: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	lumpSums := make(map[amortize.PaymentPeriod]amortize.LumpSumPayment, len(req.LumpSums))
+	for _, ls := range req.LumpSums {
+		paymentDate, err := activeLocale.ParseDate(paymentTimeFormat, ls.Date)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid lump sum date %q: %s", ls.Date, err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		pp := amortize.SnapToPaymentPeriod(paymentDate, startDate)
+		lumpSums[pp] = amortize.LumpSumPayment{PaymentPeriod: pp, PaymentDate: paymentDate, Amount: amortize.NewMoneyFromFloat64(ls.Amount)}
+	}
+
+	params := amortize.Params{
+		LoanAmount:     amortize.NewMoneyFromFloat64(req.LoanAmount),
+		StartDate:      startDate,
+		AnnualInterest: amortize.NewMoneyFromFloat64(req.Interest).Quo(amortize.NewMoneyFromInt(100)),
+		Years:          req.Years,
+		LumpSums:       lumpSums,
+	}
+
+	schedule, err := amortize.Amortize(params)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rows := make([]scheduleRowResponse, 0, len(schedule))
+	for _, row := range schedule {
+		rows = append(rows, scheduleRowResponse{
+			Date:      activeLocale.FormatDate(displayDateFormat, row.Date),
+			Type:      row.Type,
+			Interest:  FormatMoney(currency, row.Interest),
+			Principal: FormatMoney(currency, row.Principal),
+			Payment:   FormatMoney(currency, row.Payment),
+			Balance:   FormatMoney(currency, row.Balance),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(rows); err != nil {
+		log.Printf("error encoding schedule response: %s", err.Error())
+	}
+}
+
+func handleIndex(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, indexPageTemplate, cssContent)
+}
+
+// indexPageTemplate is a %s away from a complete page: a form for the loan parameters and lump sums, and a
+// script that POSTs to /schedule and re-renders the result table every time an input changes.
+const indexPageTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>wallace</title>
+<style>
+%s
+table td, table th { text-align: right; }
+table td:nth-child(1), table td:nth-child(2), table th:nth-child(1), table th:nth-child(2) { text-align: left; }
+label { display: inline-block; min-width: 9em; }
+fieldset { margin-bottom: 1em; }
+</style>
+</head>
+<body>
+<h1>Amortization schedule</h1>
+<form id="scheduleForm" onsubmit="return false">
+  <fieldset>
+    <div><label for="loanAmount">Loan amount</label><input id="loanAmount" type="number" step="0.01" value="200000"></div>
+    <div><label for="startDate">Start date</label><input id="startDate" type="text" value="January 15 2020"></div>
+    <div><label for="interest">Interest rate (%%)</label><input id="interest" type="number" step="0.001" value="4"></div>
+    <div><label for="years">Term (years)</label><input id="years" type="number" value="30"></div>
+    <div><label for="lumpSums">Lump sums (JSON)</label><input id="lumpSums" type="text" value="[]" size="40"></div>
+  </fieldset>
+</form>
+<div id="error" style="color:#c00"></div>
+<div id="result">Loading&hellip;</div>
+<script>
+var form = document.getElementById("scheduleForm");
+var result = document.getElementById("result");
+var errorBox = document.getElementById("error");
+var pending = null;
+
+function render() {
+  var body = {
+    loanAmount: parseFloat(document.getElementById("loanAmount").value),
+    startDate: document.getElementById("startDate").value,
+    interest: parseFloat(document.getElementById("interest").value),
+    years: parseInt(document.getElementById("years").value, 10),
+    lumpSums: []
+  };
+
+  try {
+    body.lumpSums = JSON.parse(document.getElementById("lumpSums").value || "[]");
+  } catch (e) {
+    errorBox.textContent = "Invalid lump sums JSON: " + e.message;
+    return;
+  }
+
+  fetch("/schedule", {method: "POST", headers: {"Content-Type": "application/json"}, body: JSON.stringify(body)})
+    .then(function(resp) {
+      if (!resp.ok) { return resp.text().then(function(t) { throw new Error(t); }); }
+      return resp.json();
+    })
+    .then(function(rows) {
+      errorBox.textContent = "";
+      var table = document.createElement("table");
+      var header = table.insertRow();
+      ["month", "type", "interest", "principal", "payment", "balance"].forEach(function(h) {
+        var th = document.createElement("th");
+        th.textContent = h;
+        header.appendChild(th);
+      });
+      rows.forEach(function(row) {
+        var tr = table.insertRow();
+        [row.date, row.type, row.interest, row.principal, row.payment, row.balance].forEach(function(v) {
+          var td = tr.insertCell();
+          td.textContent = v;
+        });
+      });
+      result.innerHTML = "";
+      result.appendChild(table);
+    })
+    .catch(function(err) { errorBox.textContent = err.message; });
+}
+
+form.addEventListener("input", function() {
+  if (pending) { clearTimeout(pending); }
+  pending = setTimeout(render, 300);
+});
+
+render();
+</script>
+</body>
+</html>
+`